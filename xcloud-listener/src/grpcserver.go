@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/cburch824/xcloud-keyboard-mouse/xcloud-listener/src/pb"
+	"google.golang.org/grpc"
+)
+
+// grpcPort is the TCP port the Input gRPC service listens on.
+var grpcPort = 9001
+
+// inputServer implements pb.InputServer on top of the same
+// performAction/send pipeline used by the HTTP action endpoint and the
+// native-messaging stdin loop.
+type inputServer struct {
+	pb.UnimplementedInputServer
+}
+
+// actionRequestToString renders an ActionRequest as the action string the
+// rest of the pipeline (performAction, send) already understands.
+func actionRequestToString(req *pb.ActionRequest) string {
+	switch req.GetKind() {
+	case "key":
+		return fmt.Sprintf("key:%s", req.GetKey())
+	case "mouse":
+		return fmt.Sprintf("mouse:%s:%d:%d", req.GetButton(), req.GetX(), req.GetY())
+	default:
+		return req.GetKind()
+	}
+}
+
+// Action performs a single action and reports the outcome.
+func (s *inputServer) Action(ctx context.Context, req *pb.ActionRequest) (*pb.ActionResponse, error) {
+	action := actionRequestToString(req)
+	if action == "" {
+		return &pb.ActionResponse{Ok: false, Error: "empty action"}, nil
+	}
+
+	performAction("grpc", action)
+	return &pb.ActionResponse{Ok: true, Echo: action}, nil
+}
+
+// StreamActions accepts a high-rate stream of actions from a controller and
+// acks each one in order as it is dispatched.
+func (s *inputServer) StreamActions(stream pb.Input_StreamActionsServer) error {
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp, err := s.Action(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// startGRPCServer starts the Input gRPC service on grpcPort. It runs for
+// the lifetime of the process, alongside the HTTP server started by
+// handleRequests. Incoming messages are bounded by MaxMessageBytes, the
+// same cap applied to stdin frames and HTTP request bodies, rather than
+// grpc-go's 4 MiB default.
+func startGRPCServer() {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+	if err != nil {
+		Error.Printf("gRPC server failed to listen on port %d: %v", grpcPort, err)
+		return
+	}
+
+	s := grpc.NewServer(grpc.MaxRecvMsgSize(MaxMessageBytes))
+	pb.RegisterInputServer(s, &inputServer{})
+
+	Trace.Printf("gRPC Input service listening on :%d", grpcPort)
+	if err := s.Serve(lis); err != nil {
+		Error.Printf("gRPC server exited: %v", err)
+	}
+}
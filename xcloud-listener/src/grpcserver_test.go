@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cburch824/xcloud-keyboard-mouse/xcloud-listener/src/pb"
+)
+
+func TestActionRequestToString(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *pb.ActionRequest
+		want string
+	}{
+		{
+			name: "key",
+			req:  &pb.ActionRequest{Kind: "key", Key: "Enter"},
+			want: "key:Enter",
+		},
+		{
+			name: "mouse",
+			req:  &pb.ActionRequest{Kind: "mouse", Button: "left", X: 10, Y: 20},
+			want: "mouse:left:10:20",
+		},
+		{
+			name: "unknown kind passes through",
+			req:  &pb.ActionRequest{Kind: "scroll"},
+			want: "scroll",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := actionRequestToString(tt.req)
+			if got != tt.want {
+				t.Fatalf("actionRequestToString(%+v) = %q, want %q", tt.req, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputServerActionRejectsEmptyAction(t *testing.T) {
+	s := &inputServer{}
+
+	resp, err := s.Action(context.Background(), &pb.ActionRequest{})
+	if err != nil {
+		t.Fatalf("Action: %v", err)
+	}
+	if resp.Ok {
+		t.Fatalf("Ok = true, want false for an empty action")
+	}
+	if resp.Error == "" {
+		t.Fatal("expected a non-empty Error message for an empty action")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// journalPath is the append-only journal of every action the host
+// dispatches, independent of xcloudListener.log (which carries free-form
+// trace/error text rather than structured records). Variable so tests
+// can point it at a temporary file.
+var journalPath = "xcloudListener.journal"
+
+// JournalRecord is one entry in the journal: a single dispatched action
+// and the response that was sent for it.
+type JournalRecord struct {
+	Seq       uint64    `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Transport string    `json:"transport"`
+	Query     string    `json:"query"`
+	Response  string    `json:"response"`
+}
+
+var (
+	journalMu      sync.Mutex
+	journalSeq     uint64
+	journalSeqInit sync.Once
+)
+
+// journalAppend appends a record of a dispatched action to journalPath.
+// Each record is written as a 4-byte big-endian length, the JSON record,
+// then the same length repeated as a trailer, so the file can be
+// efficiently scanned backwards from EOF (see tailJournal).
+func journalAppend(transport, query, response string) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	journalSeqInit.Do(initJournalSeq)
+
+	file, err := os.OpenFile(journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		Error.Printf("Unable to open journal %s: %v", journalPath, err)
+		return
+	}
+	defer file.Close()
+
+	journalSeq++
+	record := JournalRecord{
+		Seq:       journalSeq,
+		Timestamp: time.Now(),
+		Transport: transport,
+		Query:     query,
+		Response:  response,
+	}
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		Error.Printf("Unable to marshal journal record: %v", err)
+		return
+	}
+
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(body)))
+
+	if _, err := file.Write(length); err != nil {
+		Error.Printf("Unable to write journal record length: %v", err)
+		return
+	}
+	if _, err := file.Write(body); err != nil {
+		Error.Printf("Unable to write journal record body: %v", err)
+		return
+	}
+	if _, err := file.Write(length); err != nil {
+		Error.Printf("Unable to write journal record trailer: %v", err)
+		return
+	}
+}
+
+// initJournalSeq resumes the sequence counter from the last record
+// already on disk, so a restarted host doesn't reuse sequence numbers.
+// Called from journalAppend, which already holds journalMu.
+func initJournalSeq() {
+	records, err := tailJournalLocked(1)
+	if err != nil || len(records) == 0 {
+		return
+	}
+	journalSeq = records[0].Seq
+}
+
+// tailJournal returns up to the last n records in the journal, oldest
+// first, without loading the whole file: it seeks to EOF and walks
+// backwards one record at a time using each record's length trailer. It
+// takes journalMu so a concurrent journalAppend can't be observed
+// mid-write (e.g. after the length+body but before the trailer).
+func tailJournal(n int) ([]JournalRecord, error) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	return tailJournalLocked(n)
+}
+
+// tailJournalLocked is tailJournal's body, for callers that already hold
+// journalMu.
+func tailJournalLocked(n int) ([]JournalRecord, error) {
+	if n <= 0 {
+		return []JournalRecord{}, nil
+	}
+
+	file, err := os.Open(journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []JournalRecord{}, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []JournalRecord{}
+	lengthBuf := make([]byte, 4)
+
+	for len(records) < n && offset > 0 {
+		if offset < 4 {
+			return nil, fmt.Errorf("journal: truncated trailer at offset %d", offset)
+		}
+
+		if _, err := file.ReadAt(lengthBuf, offset-4); err != nil {
+			return nil, err
+		}
+		recordLen := int64(binary.BigEndian.Uint32(lengthBuf))
+
+		recordStart := offset - 4 - recordLen - 4
+		if recordStart < 0 {
+			return nil, fmt.Errorf("journal: corrupt record before offset %d", offset)
+		}
+
+		body := make([]byte, recordLen)
+		if _, err := file.ReadAt(body, recordStart+4); err != nil {
+			return nil, err
+		}
+
+		var record JournalRecord
+		if err := json.Unmarshal(body, &record); err != nil {
+			return nil, fmt.Errorf("journal: unable to unmarshal record at offset %d: %w", recordStart+4, err)
+		}
+
+		records = append([]JournalRecord{record}, records...)
+		offset = recordStart
+	}
+
+	return records, nil
+}
+
+// tailJournalJSON renders the last n journal records as a JSON array,
+// for the native-messaging "tail" query whose response must be a string.
+func tailJournalJSON(n int) string {
+	records, err := tailJournal(n)
+	if err != nil {
+		Error.Printf("Unable to tail journal: %v", err)
+		return "[]"
+	}
+
+	out, err := json.Marshal(records)
+	if err != nil {
+		Error.Printf("Unable to marshal tailed journal records: %v", err)
+		return "[]"
+	}
+	return string(out)
+}
+
+// actionsTailEndpoint serves GET /actions/tail?n=N with the last N
+// journal records as a JSON array.
+func actionsTailEndpoint(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 {
+		http.Error(w, "query parameter n must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	records, err := tailJournal(n)
+	if err != nil {
+		Trace.Printf("Error tailing journal: %v", err)
+		http.Error(w, "unable to read journal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(records); err != nil {
+		Error.Printf("Unable to encode tailed journal records: %v", err)
+	}
+}
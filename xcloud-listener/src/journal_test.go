@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// withTestJournal points journalPath at a fresh temporary file and resets
+// the in-memory sequence counter, so each test starts from a clean slate.
+func withTestJournal(t *testing.T) {
+	t.Helper()
+
+	oldPath := journalPath
+	journalPath = filepath.Join(t.TempDir(), "test.journal")
+	journalSeq = 0
+	journalSeqInit = sync.Once{}
+
+	t.Cleanup(func() { journalPath = oldPath })
+}
+
+func TestJournalAppendAndTailRoundTrip(t *testing.T) {
+	withTestJournal(t)
+
+	journalAppend("stdin", "ping", "pong")
+	journalAppend("http", "key:a", "key:a")
+	journalAppend("ws", "mouse:left:10:20", "mouse:left:10:20")
+
+	records, err := tailJournal(2)
+	if err != nil {
+		t.Fatalf("tailJournal: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("len(records) = %d, want 2", len(records))
+	}
+
+	if records[0].Query != "key:a" || records[0].Transport != "http" {
+		t.Fatalf("records[0] = %+v, want query=key:a transport=http", records[0])
+	}
+	if records[1].Query != "mouse:left:10:20" || records[1].Transport != "ws" {
+		t.Fatalf("records[1] = %+v, want query=mouse:left:10:20 transport=ws", records[1])
+	}
+	if records[0].Seq != 2 || records[1].Seq != 3 {
+		t.Fatalf("unexpected sequence numbers: %d, %d", records[0].Seq, records[1].Seq)
+	}
+}
+
+func TestTailJournalMoreThanAvailable(t *testing.T) {
+	withTestJournal(t)
+
+	journalAppend("stdin", "ping", "pong")
+
+	records, err := tailJournal(10)
+	if err != nil {
+		t.Fatalf("tailJournal: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("len(records) = %d, want 1", len(records))
+	}
+}
+
+func TestTailJournalMissingFile(t *testing.T) {
+	withTestJournal(t)
+
+	records, err := tailJournal(5)
+	if err != nil {
+		t.Fatalf("tailJournal on a nonexistent journal should not error: %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("len(records) = %d, want 0", len(records))
+	}
+}
+
+func TestJournalSeqResumesAfterRestart(t *testing.T) {
+	withTestJournal(t)
+
+	journalAppend("stdin", "ping", "pong")
+	journalAppend("stdin", "ping", "pong")
+
+	// Simulate a process restart: drop the in-memory counter and the
+	// sync.Once guarding it, but keep the on-disk journal.
+	journalSeq = 0
+	journalSeqInit = sync.Once{}
+
+	journalAppend("stdin", "ping", "pong")
+
+	records, err := tailJournal(1)
+	if err != nil {
+		t.Fatalf("tailJournal: %v", err)
+	}
+	if len(records) != 1 || records[0].Seq != 3 {
+		t.Fatalf("expected sequence to resume at 3, got %+v", records)
+	}
+}
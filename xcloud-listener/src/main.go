@@ -7,16 +7,18 @@ package main
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"sync"
 	"unsafe"
+
+	"github.com/cburch824/xcloud-keyboard-mouse/xcloud-listener/src/messageio"
 )
 
 // constants for Logger
@@ -30,12 +32,33 @@ var (
 // nativeEndian used to detect native byte order
 var nativeEndian binary.ByteOrder
 
+// stdoutMu serializes writes to os.Stdout: gRPC, WebSocket, and HTTP
+// handlers all call send() from independent goroutines, and an
+// interleaved write would corrupt the native-messaging byte stream.
+var stdoutMu sync.Mutex
+
 // bufferSize used to set size of IO buffer - adjust to accommodate message payloads
 var bufferSize = 8192
 
+// MaxMessageBytes bounds the size of any single incoming message across
+// every ingress path (native-messaging stdin frames, HTTP request
+// bodies, gRPC messages and WebSocket payloads), so that a hostile or
+// buggy sender can't force an oversized allocation.
+var MaxMessageBytes = messageio.DefaultMaxMessageBytes
+
+// compressOpts controls whether outgoing native-messaging frames are
+// LZ4-compressed. Disabled by default; incoming frames are always
+// decompressed transparently when the compressed envelope is present.
+var compressOpts = messageio.CompressOpts{
+	Enabled:   false,
+	Threshold: 1024,
+}
+
 // IncomingMessage represents a message sent to the native host.
 type IncomingMessage struct {
 	Query string `json:"query"`
+	// N is the record count for a "tail" query.
+	N int `json:"n,omitempty"`
 }
 
 // OutgoingMessage respresents a response to an incoming message query.
@@ -60,6 +83,17 @@ func Init(traceHandle io.Writer, errorHandle io.Writer) {
 }
 
 func main() {
+	flag.IntVar(&grpcPort, "grpc-port", grpcPort, "TCP port for the Input gRPC service")
+	flag.BoolVar(&compressOpts.Enabled, "compress", compressOpts.Enabled, "LZ4-compress outgoing native-messaging frames above the compression threshold")
+	flag.IntVar(&MaxMessageBytes, "max-message-bytes", MaxMessageBytes, "maximum size in bytes of a single incoming message on any ingress path")
+	flag.Parse()
+
+	// Bound LZ4 decompression by the same ceiling as everything else,
+	// unless a positive cap has already been set.
+	if compressOpts.MaxDecompressedBytes <= 0 {
+		compressOpts.MaxDecompressedBytes = MaxMessageBytes
+	}
+
 	log.Println("Starting native messaging host")
 	file, err := os.OpenFile("xcloudListener.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	log.Println(err)
@@ -75,6 +109,8 @@ func main() {
 	}
 
 	Trace.Printf("Chrome native messaging host started. Native byte order: %v.", nativeEndian)
+	go startGRPCServer()
+	go read()
 	handleRequests()
 	Trace.Print("Chrome native messaging host exited.")
 }
@@ -86,24 +122,29 @@ func homeEndpoint(w http.ResponseWriter, r *http.Request) {
 }
 
 func actionEndpoint(w http.ResponseWriter, r *http.Request) {
-	reqBody, err := ioutil.ReadAll(r.Body)
+	r.Body = http.MaxBytesReader(w, r.Body, int64(MaxMessageBytes))
+	reqBody, err := io.ReadAll(r.Body)
 	if err != nil {
 		Trace.Printf("Error reading action body: %s", err.Error())
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
 		return
 	}
 
-	performAction(string(reqBody[:]))
+	performAction("http", string(reqBody[:]))
 }
 
-func performAction(action string) {
+// performAction dispatches action, originating from the named transport
+// (e.g. "stdin", "http", "grpc", "ws"), and journals the result.
+func performAction(transport, action string) {
 	if action == "" {
 		Trace.Printf("Action string is empty")
 		return
 	}
-	
+
 	Trace.Printf("Performing action: %s", action)
 	msg := OutgoingMessage{ Query: action, Response: action}
 	send(msg)
+	journalAppend(transport, msg.Query, msg.Response)
 	Trace.Printf("Message query: %s", msg.Query)
 	Trace.Printf("Message response: %s", msg.Response)
 }
@@ -111,6 +152,8 @@ func performAction(action string) {
 func handleRequests() {
 	http.HandleFunc("/", homeEndpoint)
 	http.HandleFunc("/action", actionEndpoint)
+	http.HandleFunc("/ws", websocketEndpoint)
+	http.HandleFunc("/actions/tail", actionsTailEndpoint)
 	
 	log.Fatal(http.ListenAndServe(":9000", nil))
 }
@@ -122,27 +165,16 @@ func read() {
 	s := bufio.NewReaderSize(v, bufferSize)
 	Trace.Printf("IO buffer reader created with buffer size of %v.", s.Size())
 
-	lengthBytes := make([]byte, 4)
-	lengthNum := int(0)
-
-	// we're going to indefinitely read the first 4 bytes in buffer, which gives us the message length.
+	// we're going to indefinitely read framed messages from stdin; a frame
+	// is an opaque, optionally LZ4-compressed envelope (see messageio).
 	// if stdIn is closed we'll exit the loop and shut down host
-	for b, err := s.Read(lengthBytes); b > 0 && err == nil; b, err = s.Read(lengthBytes) {
-		// convert message length bytes to integer value
-		lengthNum = readMessageLength(lengthBytes)
-		Trace.Printf("Message size in bytes: %v", lengthNum)
-
-		// If message length exceeds size of buffer, the message will be truncated.
-		// This will likely cause an error when we attempt to unmarshal message to JSON.
-		if lengthNum > bufferSize {
-			Error.Printf("Message size of %d exceeds buffer size of %d. Message will be truncated and is unlikely to unmarshal to JSON.", lengthNum, bufferSize)
-		}
-
-		// read the content of the message from buffer
-		content := make([]byte, lengthNum)
-		_, err := s.Read(content)
-		if err != nil && err != io.EOF {
-			Error.Fatal(err)
+	for {
+		content, err := messageio.ReadFramed(s, nativeEndian, MaxMessageBytes, compressOpts)
+		if err != nil {
+			if err != io.EOF {
+				Error.Printf("Unable to read framed message from Stdin: %v", err)
+			}
+			break
 		}
 
 		// message has been read, now parse and process
@@ -152,17 +184,6 @@ func read() {
 	Trace.Print("Stdin closed.")
 }
 
-// readMessageLength reads and returns the message length value in native byte order.
-func readMessageLength(msg []byte) int {
-	var length uint32
-	buf := bytes.NewBuffer(msg)
-	err := binary.Read(buf, nativeEndian, &length)
-	if err != nil {
-		Error.Printf("Unable to read bytes representing message length: %v", err)
-	}
-	return int(length)
-}
-
 // parseMessage parses incoming message
 func parseMessage(msg []byte) {
 	iMsg := decodeMessage(msg)
@@ -178,11 +199,14 @@ func parseMessage(msg []byte) {
 		oMsg.Response = "pong"
 	case "hello":
 		oMsg.Response = "goodbye"
+	case "tail":
+		oMsg.Response = tailJournalJSON(iMsg.N)
 	default:
 		oMsg.Response = "42"
 	}
 
 	send(oMsg)
+	journalAppend("stdin", oMsg.Query, oMsg.Response)
 }
 
 // decodeMessage unmarshals incoming json request and returns query value.
@@ -195,20 +219,16 @@ func decodeMessage(msg []byte) IncomingMessage {
 	return iMsg
 }
 
-// send sends an OutgoingMessage to os.Stdout.
+// send sends an OutgoingMessage to os.Stdout, framed (and optionally
+// LZ4-compressed) per messageio.
 func send(msg OutgoingMessage) {
 	byteMsg := msgTextToBytes(msg.Response)
-	writeMessageLength(byteMsg)
 
-	var msgBuf bytes.Buffer
-	_, err := msgBuf.Write(byteMsg)
-	if err != nil {
-		Error.Printf("Unable to write message length to message buffer: %v", err)
-	}
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
 
-	_, err = msgBuf.WriteTo(os.Stdout)
-	if err != nil {
-		Error.Printf("Unable to write message buffer to Stdout: %v", err)
+	if err := messageio.WriteFramed(os.Stdout, nativeEndian, byteMsg, compressOpts); err != nil {
+		Error.Printf("Unable to write framed message to Stdout: %v", err)
 	}
 }
 
@@ -229,11 +249,3 @@ func dataToBytes(msg OutgoingMessage) []byte {
 	}
 	return byteMsg
 }
-
-// writeMessageLength determines length of message and writes it to os.Stdout.
-func writeMessageLength(msg []byte) {
-	err := binary.Write(os.Stdout, nativeEndian, uint32(len(msg)))
-	if err != nil {
-		Error.Printf("Unable to write message length to Stdout: %v", err)
-	}
-}
@@ -0,0 +1,163 @@
+// Package messageio implements the framed message envelope used by the
+// Chrome native-messaging stdin/stdout loop of the native messaging host.
+// The HTTP, gRPC and WebSocket transports do not use it.
+//
+// A frame is a 4-byte outer length (in the caller-supplied byte order)
+// followed by a body. The body is either today's plain JSON, or an
+// opt-in compressed envelope: a 12-byte header of three uint32s in
+// network byte order (magic, compressed length, uncompressed length)
+// followed by the LZ4-compressed payload.
+package messageio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+// Magic identifies a compressed envelope. If a body does not begin with
+// this magic number, it is treated as plain, uncompressed JSON.
+const Magic uint32 = 0x5e63b278
+
+// envelopeHeaderSize is the size in bytes of the magic/compressed-length/
+// uncompressed-length header that precedes a compressed payload.
+const envelopeHeaderSize = 12
+
+// DefaultMaxMessageBytes is used when CompressOpts.MaxDecompressedBytes is
+// left unset, to bound allocation when decompressing a frame.
+const DefaultMaxMessageBytes = 1 << 20 // 1 MiB
+
+// CompressOpts controls whether WriteFramed compresses a payload, and the
+// limits ReadFramed enforces when decompressing one.
+type CompressOpts struct {
+	// Enabled gates compression on Write. When false, WriteFramed always
+	// writes plain JSON.
+	Enabled bool
+
+	// Threshold is the payload size, in bytes, that must be exceeded
+	// before compression is applied. Payloads of Threshold bytes or fewer
+	// are written as plain JSON even when Enabled is true, since LZ4 framing overhead isn't
+	// worth it for small messages.
+	Threshold int
+
+	// MaxDecompressedBytes caps the size ReadFramed will allocate when
+	// decompressing a frame, to avoid zip-bomb-style allocations. Zero
+	// means DefaultMaxMessageBytes.
+	MaxDecompressedBytes int
+}
+
+// ReadFramed reads one length-prefixed frame from r: a 4-byte outer
+// length in order, followed by the body. If the body is absent, the
+// length exceeds maxBytes, or the declared length prefix is a partial
+// read, a non-nil error is returned. Bodies carrying the compressed
+// envelope are transparently decompressed, bounded by
+// opts.MaxDecompressedBytes rather than maxBytes, since a small
+// compressed frame can still decompress to something much larger.
+func ReadFramed(r io.Reader, order binary.ByteOrder, maxBytes int, opts CompressOpts) ([]byte, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxMessageBytes
+	}
+
+	lengthBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r, lengthBytes); err != nil {
+		return nil, err
+	}
+
+	length := int(order.Uint32(lengthBytes))
+	if length > maxBytes {
+		return nil, fmt.Errorf("messageio: frame length %d exceeds maximum of %d bytes", length, maxBytes)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("messageio: short read of framed body: %w", err)
+	}
+
+	return decodeEnvelope(body, opts.MaxDecompressedBytes)
+}
+
+// decodeEnvelope strips and decompresses the compressed envelope if
+// present, or returns body unchanged if it is plain JSON.
+func decodeEnvelope(body []byte, maxDecompressedBytes int) ([]byte, error) {
+	if len(body) < envelopeHeaderSize {
+		return body, nil
+	}
+
+	magic := binary.BigEndian.Uint32(body[0:4])
+	if magic != Magic {
+		return body, nil
+	}
+
+	compressedLen := binary.BigEndian.Uint32(body[4:8])
+	uncompressedLen := binary.BigEndian.Uint32(body[8:12])
+
+	if maxDecompressedBytes <= 0 {
+		maxDecompressedBytes = DefaultMaxMessageBytes
+	}
+	if uncompressedLen > uint32(maxDecompressedBytes) {
+		return nil, fmt.Errorf("messageio: declared uncompressed size %d exceeds maximum of %d bytes", uncompressedLen, maxDecompressedBytes)
+	}
+
+	payload := body[envelopeHeaderSize:]
+	if uint32(len(payload)) != compressedLen {
+		return nil, fmt.Errorf("messageio: compressed payload length %d does not match header length %d", len(payload), compressedLen)
+	}
+
+	decompressed := make([]byte, uncompressedLen)
+	n, err := lz4.UncompressBlock(payload, decompressed)
+	if err != nil {
+		return nil, fmt.Errorf("messageio: lz4 decompress failed: %w", err)
+	}
+	if uint32(n) != uncompressedLen {
+		return nil, fmt.Errorf("messageio: decompressed size %d does not match header's declared size %d", n, uncompressedLen)
+	}
+
+	return decompressed, nil
+}
+
+// WriteFramed writes payload to w as one length-prefixed frame. When
+// opts.Enabled is set and len(payload) exceeds opts.Threshold, payload is
+// LZ4-compressed and wrapped in the compressed envelope; otherwise it is
+// written as plain JSON.
+func WriteFramed(w io.Writer, order binary.ByteOrder, payload []byte, opts CompressOpts) error {
+	body := payload
+	if opts.Enabled && len(payload) > opts.Threshold {
+		compressed, err := compressEnvelope(payload)
+		if err != nil {
+			return err
+		}
+		body = compressed
+	}
+
+	lengthBytes := make([]byte, 4)
+	order.PutUint32(lengthBytes, uint32(len(body)))
+
+	var buf bytes.Buffer
+	buf.Write(lengthBytes)
+	buf.Write(body)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// compressEnvelope LZ4-compresses payload and wraps it in the 12-byte
+// magic/compressed-length/uncompressed-length header.
+func compressEnvelope(payload []byte) ([]byte, error) {
+	compressed := make([]byte, lz4.CompressBlockBound(len(payload)))
+	var c lz4.Compressor
+	n, err := c.CompressBlock(payload, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("messageio: lz4 compress failed: %w", err)
+	}
+	compressed = compressed[:n]
+
+	header := make([]byte, envelopeHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], Magic)
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(compressed)))
+	binary.BigEndian.PutUint32(header[8:12], uint32(len(payload)))
+
+	return append(header, compressed...), nil
+}
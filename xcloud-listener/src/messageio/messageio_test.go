@@ -0,0 +1,118 @@
+package messageio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestWriteReadFramedPlain(t *testing.T) {
+	payload := []byte(`{"query":"ping"}`)
+
+	var buf bytes.Buffer
+	if err := WriteFramed(&buf, binary.LittleEndian, payload, CompressOpts{}); err != nil {
+		t.Fatalf("WriteFramed: %v", err)
+	}
+
+	got, err := ReadFramed(&buf, binary.LittleEndian, 0, CompressOpts{})
+	if err != nil {
+		t.Fatalf("ReadFramed: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("round trip mismatch: got %q, want %q", got, payload)
+	}
+}
+
+func TestWriteReadFramedCompressed(t *testing.T) {
+	payload := []byte(strings.Repeat("macro step, ", 200))
+	opts := CompressOpts{Enabled: true, Threshold: 16}
+
+	var buf bytes.Buffer
+	if err := WriteFramed(&buf, binary.BigEndian, payload, opts); err != nil {
+		t.Fatalf("WriteFramed: %v", err)
+	}
+
+	raw := buf.Bytes()
+	body := raw[4:]
+	if binary.BigEndian.Uint32(body[0:4]) != Magic {
+		t.Fatalf("expected compressed envelope magic, got plain body")
+	}
+
+	got, err := ReadFramed(&buf, binary.BigEndian, 0, opts)
+	if err != nil {
+		t.Fatalf("ReadFramed: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("round trip mismatch after decompression: got %q, want %q", got, payload)
+	}
+}
+
+func TestWriteFramedBelowThresholdStaysPlain(t *testing.T) {
+	payload := []byte("short")
+	opts := CompressOpts{Enabled: true, Threshold: 1024}
+
+	var buf bytes.Buffer
+	if err := WriteFramed(&buf, binary.BigEndian, payload, opts); err != nil {
+		t.Fatalf("WriteFramed: %v", err)
+	}
+
+	body := buf.Bytes()[4:]
+	if !bytes.Equal(body, payload) {
+		t.Fatalf("expected plain body below threshold, got %x", body)
+	}
+}
+
+func TestReadFramedRejectsOversizedFrame(t *testing.T) {
+	var buf bytes.Buffer
+	lengthBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(lengthBytes, 1<<20)
+	buf.Write(lengthBytes)
+
+	if _, err := ReadFramed(&buf, binary.BigEndian, 1024, CompressOpts{}); err == nil {
+		t.Fatal("expected an error for a frame declaring a length over maxBytes")
+	}
+}
+
+func TestReadFramedRejectsOversizedDecompressedSizeIndependentlyOfMaxBytes(t *testing.T) {
+	// A small, well-formed compressed frame that still declares an
+	// oversized uncompressed length must be rejected by
+	// opts.MaxDecompressedBytes even though it easily fits under maxBytes.
+	header := make([]byte, envelopeHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], Magic)
+	binary.BigEndian.PutUint32(header[4:8], 0)
+	binary.BigEndian.PutUint32(header[8:12], 1<<20)
+
+	var buf bytes.Buffer
+	lengthBytes := make([]byte, 4)
+	binary.BigEndian.PutUint32(lengthBytes, uint32(len(header)))
+	buf.Write(lengthBytes)
+	buf.Write(header)
+
+	opts := CompressOpts{MaxDecompressedBytes: 1024}
+	if _, err := ReadFramed(&buf, binary.BigEndian, 1<<20, opts); err == nil {
+		t.Fatal("expected an error when the declared uncompressed size exceeds opts.MaxDecompressedBytes, even though maxBytes is generous")
+	}
+}
+
+func TestDecodeEnvelopeRejectsOversizedHeader(t *testing.T) {
+	header := make([]byte, envelopeHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], Magic)
+	binary.BigEndian.PutUint32(header[4:8], 0)
+	binary.BigEndian.PutUint32(header[8:12], 1<<30) // declares a huge uncompressed size
+
+	if _, err := decodeEnvelope(header, 1024); err == nil {
+		t.Fatal("expected an error when the declared uncompressed size exceeds the cap")
+	}
+}
+
+func TestDecodeEnvelopeRejectsMismatchedCompressedLength(t *testing.T) {
+	header := make([]byte, envelopeHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], Magic)
+	binary.BigEndian.PutUint32(header[4:8], 99) // doesn't match the (empty) payload that follows
+	binary.BigEndian.PutUint32(header[8:12], 0)
+
+	if _, err := decodeEnvelope(header, 0); err == nil {
+		t.Fatal("expected an error when the compressed payload length doesn't match the header")
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// websocketGUID is the magic GUID used to compute Sec-WebSocket-Accept, per
+// RFC 6455 section 1.3.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes, per RFC 6455 section 5.2.
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+)
+
+// WebSocket close status codes, per RFC 6455 section 7.4.1.
+const (
+	wsStatusNormalClosure  = 1000
+	wsStatusGoingAway      = 1001
+	wsStatusMessageTooBig  = 1009
+	wsStatusProtocolError  = 1002
+	wsStatusUnsupportedUTF = 1007
+)
+
+// wsCloseError is returned by readWebSocketFrame/readWebSocketMessage for
+// a framing violation that RFC 6455 requires closing the connection
+// over, carrying the status code the Close frame should report.
+type wsCloseError struct {
+	code int
+	err  error
+}
+
+func (e *wsCloseError) Error() string { return e.err.Error() }
+
+// InputEvent is an incoming WebSocket message describing a single
+// keyboard/mouse event to feed into performAction.
+type InputEvent struct {
+	Type      string   `json:"type"`
+	Key       string   `json:"key"`
+	Button    string   `json:"button"`
+	X         int      `json:"x"`
+	Y         int      `json:"y"`
+	Modifiers []string `json:"modifiers"`
+}
+
+// websocketEndpoint upgrades the connection to a WebSocket and streams
+// InputEvents into performAction for the lifetime of the connection.
+func websocketEndpoint(w http.ResponseWriter, r *http.Request) {
+	conn, brw, err := websocketUpgrade(w, r)
+	if err != nil {
+		Trace.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	Trace.Print("Endpoint hit: websocketEndpoint")
+	for {
+		opcode, payload, err := readWebSocketMessage(brw)
+		if err != nil {
+			Trace.Printf("WebSocket read error: %v", err)
+			var closeErr *wsCloseError
+			if errors.As(err, &closeErr) {
+				writeWebSocketClose(brw.Writer, closeErr.code)
+			}
+			return
+		}
+
+		switch opcode {
+		case wsOpClose:
+			code := wsStatusNormalClosure
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload[:2]))
+			}
+			writeWebSocketClose(brw.Writer, code)
+			return
+		case wsOpText:
+			if !utf8.Valid(payload) {
+				writeWebSocketClose(brw.Writer, wsStatusUnsupportedUTF)
+				return
+			}
+
+			var evt InputEvent
+			if err := json.Unmarshal(payload, &evt); err != nil {
+				Error.Printf("Unable to unmarshal WebSocket InputEvent: %v", err)
+				continue
+			}
+
+			performAction("ws", inputEventToAction(evt))
+			oMsg := OutgoingMessage{Query: evt.Type, Response: "ok"}
+			out, err := json.Marshal(oMsg)
+			if err != nil {
+				Error.Printf("Unable to marshal OutgoingMessage for WebSocket: %v", err)
+				continue
+			}
+			writeWebSocketFrame(brw.Writer, wsOpText, out)
+		}
+	}
+}
+
+// inputEventToAction renders an InputEvent as the action string the rest
+// of the pipeline (performAction, send) already understands.
+func inputEventToAction(evt InputEvent) string {
+	switch evt.Type {
+	case "key":
+		return fmt.Sprintf("key:%s", evt.Key)
+	case "mouse":
+		return fmt.Sprintf("mouse:%s:%d:%d", evt.Button, evt.X, evt.Y)
+	default:
+		return evt.Type
+	}
+}
+
+// websocketUpgrade performs the WebSocket opening handshake and hijacks
+// the underlying connection for framed reads/writes.
+func websocketUpgrade(w http.ResponseWriter, r *http.Request) (io.Closer, *bufio.ReadWriter, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		http.Error(w, "expected websocket upgrade", http.StatusBadRequest)
+		return nil, nil, fmt.Errorf("missing Upgrade: websocket header")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, nil, fmt.Errorf("missing Sec-WebSocket-Key header")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection does not support hijacking", http.StatusInternalServerError)
+		return nil, nil, fmt.Errorf("ResponseWriter does not support Hijack")
+	}
+
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accept := websocketAcceptKey(key)
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n"
+
+	if _, err := brw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, brw, nil
+}
+
+// websocketAcceptKey computes the Sec-WebSocket-Accept header value for a
+// given Sec-WebSocket-Key, per RFC 6455 section 4.2.2.
+func websocketAcceptKey(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// readWebSocketMessage reads a complete message from the client,
+// reassembling fragmented messages via continuation frames, and enforces
+// the framing invariants required by RFC 6455: client frames must be
+// masked, control frames must not be fragmented or exceed 125 bytes, and
+// the reassembled payload must not exceed MaxMessageBytes.
+//
+// Ping/pong frames are handled in place (a pong is written immediately
+// for each ping) rather than returned, since RFC 6455 permits them to
+// appear interleaved between the fragments of a data message; returning
+// out of the loop on one would otherwise discard the fragments already
+// accumulated. Only a complete data message or a Close frame is
+// returned to the caller.
+func readWebSocketMessage(rw *bufio.ReadWriter) (byte, []byte, error) {
+	var messageOpcode byte
+	var payload []byte
+
+	for {
+		opcode, fin, masked, frame, err := readWebSocketFrame(rw.Reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !masked {
+			return 0, nil, &wsCloseError{wsStatusProtocolError, fmt.Errorf("received unmasked frame from client")}
+		}
+
+		if opcode == wsOpPing || opcode == wsOpPong || opcode == wsOpClose {
+			if !fin {
+				return 0, nil, &wsCloseError{wsStatusProtocolError, fmt.Errorf("control frame must not be fragmented")}
+			}
+			if len(frame) > 125 {
+				return 0, nil, &wsCloseError{wsStatusProtocolError, fmt.Errorf("control frame payload exceeds 125 bytes")}
+			}
+
+			switch opcode {
+			case wsOpPing:
+				if err := writeWebSocketFrame(rw.Writer, wsOpPong, frame); err != nil {
+					return 0, nil, err
+				}
+				continue
+			case wsOpPong:
+				continue
+			default: // wsOpClose
+				return opcode, frame, nil
+			}
+		}
+
+		if opcode != wsOpContinuation {
+			messageOpcode = opcode
+		}
+
+		payload = append(payload, frame...)
+		if len(payload) > MaxMessageBytes {
+			return 0, nil, &wsCloseError{wsStatusMessageTooBig, fmt.Errorf("message exceeds maximum size of %d bytes", MaxMessageBytes)}
+		}
+
+		if fin {
+			return messageOpcode, payload, nil
+		}
+	}
+}
+
+// readWebSocketFrame reads and unmasks a single WebSocket frame.
+func readWebSocketFrame(r *bufio.Reader) (opcode byte, fin bool, masked bool, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked = header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	if length > uint64(MaxMessageBytes) {
+		err = &wsCloseError{wsStatusMessageTooBig, fmt.Errorf("frame length %d exceeds maximum of %d bytes", length, MaxMessageBytes)}
+		return
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, maskKey[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+
+	return
+}
+
+// writeWebSocketFrame writes an unmasked server-to-client frame; servers
+// never mask outgoing frames, per RFC 6455 section 5.1.
+func writeWebSocketFrame(w *bufio.Writer, opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		ext := make([]byte, 2)
+		binary.BigEndian.PutUint16(ext, uint16(len(payload)))
+		header = append(header, 126)
+		header = append(header, ext...)
+	default:
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(len(payload)))
+		header = append(header, 127)
+		header = append(header, ext...)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// writeWebSocketClose sends a Close frame with the given status code.
+func writeWebSocketClose(w *bufio.Writer, code int) error {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return writeWebSocketFrame(w, wsOpClose, payload)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// newTestReadWriter wraps r in a bufio.ReadWriter whose writer side
+// discards output, so readWebSocketMessage (which replies to pings
+// in place) can be exercised without a real connection.
+func newTestReadWriter(r io.Reader) *bufio.ReadWriter {
+	return bufio.NewReadWriter(bufio.NewReader(r), bufio.NewWriter(io.Discard))
+}
+
+// maskedClientFrame builds a single masked WebSocket frame, as a
+// compliant client would send it.
+func maskedClientFrame(fin bool, opcode byte, payload []byte) []byte {
+	var buf bytes.Buffer
+
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	buf.WriteByte(first)
+
+	maskKey := [4]byte{0x12, 0x34, 0x56, 0x78}
+	switch {
+	case len(payload) < 126:
+		buf.WriteByte(0x80 | byte(len(payload)))
+	case len(payload) <= 0xffff:
+		buf.WriteByte(0x80 | 126)
+		ext := make([]byte, 2)
+		binary.BigEndian.PutUint16(ext, uint16(len(payload)))
+		buf.Write(ext)
+	default:
+		buf.WriteByte(0x80 | 127)
+		ext := make([]byte, 8)
+		binary.BigEndian.PutUint64(ext, uint64(len(payload)))
+		buf.Write(ext)
+	}
+	buf.Write(maskKey[:])
+
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ maskKey[i%4]
+	}
+	buf.Write(masked)
+
+	return buf.Bytes()
+}
+
+func TestReadWebSocketMessageFragmented(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(maskedClientFrame(false, wsOpText, []byte("hello ")))
+	stream.Write(maskedClientFrame(true, wsOpContinuation, []byte("world")))
+
+	opcode, payload, err := readWebSocketMessage(newTestReadWriter(&stream))
+	if err != nil {
+		t.Fatalf("readWebSocketMessage: %v", err)
+	}
+	if opcode != wsOpText {
+		t.Fatalf("opcode = %d, want wsOpText", opcode)
+	}
+	if string(payload) != "hello world" {
+		t.Fatalf("payload = %q, want %q", payload, "hello world")
+	}
+}
+
+func TestReadWebSocketMessageSurvivesInterleavedPing(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(maskedClientFrame(false, wsOpText, []byte("hello ")))
+	stream.Write(maskedClientFrame(true, wsOpPing, []byte("are you there")))
+	stream.Write(maskedClientFrame(true, wsOpContinuation, []byte("world")))
+
+	opcode, payload, err := readWebSocketMessage(newTestReadWriter(&stream))
+	if err != nil {
+		t.Fatalf("readWebSocketMessage: %v", err)
+	}
+	if opcode != wsOpText {
+		t.Fatalf("opcode = %d, want wsOpText", opcode)
+	}
+	if string(payload) != "hello world" {
+		t.Fatalf("payload = %q, want %q (fragment accumulation should survive an interleaved ping)", payload, "hello world")
+	}
+}
+
+func TestReadWebSocketMessageRejectsUnmaskedFrame(t *testing.T) {
+	frame := maskedClientFrame(true, wsOpText, []byte("hi"))
+	// Clear the mask bit to simulate a non-compliant client.
+	frame[1] &^= 0x80
+
+	_, _, err := readWebSocketMessage(newTestReadWriter(bytes.NewReader(frame)))
+	if err == nil {
+		t.Fatal("expected an error for an unmasked client frame")
+	}
+
+	var closeErr *wsCloseError
+	if !errors.As(err, &closeErr) || closeErr.code != wsStatusProtocolError {
+		t.Fatalf("expected a wsCloseError with code %d, got %v", wsStatusProtocolError, err)
+	}
+}
+
+func TestReadWebSocketMessageRejectsOversizedControlFrame(t *testing.T) {
+	frame := maskedClientFrame(true, wsOpPing, bytes.Repeat([]byte("x"), 126))
+
+	_, _, err := readWebSocketMessage(newTestReadWriter(bytes.NewReader(frame)))
+	if err == nil {
+		t.Fatal("expected an error for a control frame payload over 125 bytes")
+	}
+
+	var closeErr *wsCloseError
+	if !errors.As(err, &closeErr) || closeErr.code != wsStatusProtocolError {
+		t.Fatalf("expected a wsCloseError with code %d, got %v", wsStatusProtocolError, err)
+	}
+}
+
+func TestReadWebSocketMessageRejectsOversizedMessage(t *testing.T) {
+	old := MaxMessageBytes
+	MaxMessageBytes = 4
+	defer func() { MaxMessageBytes = old }()
+
+	frame := maskedClientFrame(true, wsOpText, []byte("too long"))
+
+	_, _, err := readWebSocketMessage(newTestReadWriter(bytes.NewReader(frame)))
+	if err == nil {
+		t.Fatal("expected an error for a message exceeding MaxMessageBytes")
+	}
+
+	var closeErr *wsCloseError
+	if !errors.As(err, &closeErr) || closeErr.code != wsStatusMessageTooBig {
+		t.Fatalf("expected a wsCloseError with code %d, got %v", wsStatusMessageTooBig, err)
+	}
+}